@@ -0,0 +1,63 @@
+package backend
+
+import (
+	"sync"
+	"time"
+)
+
+// breaker is a minimal circuit breaker: after failureThreshold consecutive
+// failures it opens and rejects calls for openFor, then allows a single
+// trial call through (half-open) to decide whether to close again.
+type breaker struct {
+	failureThreshold int
+	openFor          time.Duration
+
+	mu            sync.Mutex
+	failures      int
+	openSince     time.Time
+	isOpen        bool
+	trialInFlight bool
+}
+
+func newBreaker(failureThreshold int, openFor time.Duration) *breaker {
+	return &breaker{failureThreshold: failureThreshold, openFor: openFor}
+}
+
+// allow reports whether a call should proceed. It returns true at most
+// once for a trial call while the breaker is open.
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.isOpen {
+		return true
+	}
+	if time.Since(b.openSince) < b.openFor {
+		return false
+	}
+	if b.trialInFlight {
+		return false
+	}
+	b.trialInFlight = true
+	return true
+}
+
+// record reports the outcome of a call that allow permitted.
+func (b *breaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trialInFlight = false
+
+	if success {
+		b.failures = 0
+		b.isOpen = false
+		return
+	}
+
+	b.failures++
+	if b.isOpen || b.failures >= b.failureThreshold {
+		b.isOpen = true
+		b.openSince = time.Now()
+	}
+}
@@ -0,0 +1,78 @@
+package backend
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	b := newBreaker(3, 50*time.Millisecond)
+
+	for i := 0; i < 2; i++ {
+		if !b.allow() {
+			t.Fatalf("call %d: expected breaker to allow calls below the failure threshold", i)
+		}
+		b.record(false)
+	}
+
+	if !b.allow() {
+		t.Fatal("expected the third call to still be allowed before it fails")
+	}
+	b.record(false)
+
+	if b.allow() {
+		t.Fatal("expected breaker to be open and reject calls once the failure threshold is reached")
+	}
+}
+
+func TestBreakerHalfOpenAllowsOneTrial(t *testing.T) {
+	b := newBreaker(1, 20*time.Millisecond)
+
+	b.allow()
+	b.record(false) // opens the breaker
+
+	if b.allow() {
+		t.Fatal("expected breaker to reject calls immediately after opening")
+	}
+
+	time.Sleep(25 * time.Millisecond)
+
+	if !b.allow() {
+		t.Fatal("expected breaker to allow a single trial call once openFor has elapsed")
+	}
+	if b.allow() {
+		t.Fatal("expected breaker to reject a second concurrent call while a trial is in flight")
+	}
+}
+
+func TestBreakerClosesOnSuccessfulTrial(t *testing.T) {
+	b := newBreaker(1, 10*time.Millisecond)
+
+	b.allow()
+	b.record(false) // opens the breaker
+	time.Sleep(15 * time.Millisecond)
+
+	if !b.allow() {
+		t.Fatal("expected the trial call to be allowed")
+	}
+	b.record(true)
+
+	if !b.allow() {
+		t.Fatal("expected breaker to be closed again after a successful trial")
+	}
+}
+
+func TestBreakerReopensOnFailedTrial(t *testing.T) {
+	b := newBreaker(1, 10*time.Millisecond)
+
+	b.allow()
+	b.record(false) // opens the breaker
+	time.Sleep(15 * time.Millisecond)
+
+	b.allow()
+	b.record(false) // trial fails, should reopen and restart the openFor window
+
+	if b.allow() {
+		t.Fatal("expected breaker to stay open immediately after a failed trial")
+	}
+}
@@ -0,0 +1,207 @@
+// Package backend is a typed HTTP client for the guestbook backend API. It
+// centralizes timeouts, retries, and circuit breaking so that handlers in
+// the frontend package don't each reimplement backend-call resilience.
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Entry is a guestbook message as stored and returned by the backend API.
+type Entry struct {
+	Author  string    `json:"author"`
+	Message string    `json:"message"`
+	Date    time.Time `json:"date"`
+}
+
+// BadRequestError wraps a 4xx response from the backend. It indicates the
+// caller sent bad input and should not be retried.
+type BadRequestError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *BadRequestError) Error() string {
+	return fmt.Sprintf("backend rejected request with status %d: %s", e.StatusCode, e.Body)
+}
+
+// UnavailableError indicates the backend could not be reached or returned a
+// 5xx response after retries. Callers should surface a 503 to the user.
+type UnavailableError struct {
+	Err error
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("backend unavailable: %v", e.Err)
+}
+
+func (e *UnavailableError) Unwrap() error { return e.Err }
+
+// Client is a resilient client for the guestbook backend API. It owns the
+// *http.Client used for all calls, wraps idempotent GETs in a
+// retry-with-backoff loop, and fails fast via a circuit breaker once the
+// backend has been unreachable for a while.
+type Client struct {
+	addr       string
+	httpClient *http.Client
+	breaker    *breaker
+	retry      retryPolicy
+}
+
+// New builds a Client that talks to the backend at addr using httpClient.
+// addr is a host:port pair, as provided via GUESTBOOK_API_ADDR.
+func New(addr string, httpClient *http.Client) *Client {
+	return &Client{
+		addr:       addr,
+		httpClient: httpClient,
+		breaker:    newBreaker(5, 30*time.Second),
+		retry:      defaultRetryPolicy,
+	}
+}
+
+// ListMessages fetches a page of messages from the backend, forwarding
+// paging and search parameters as query string values.
+func (c *Client) ListMessages(ctx context.Context, page, limit int, query string) ([]Entry, error) {
+	v := url.Values{}
+	v.Set("page", strconv.Itoa(page))
+	v.Set("limit", strconv.Itoa(limit))
+	if query != "" {
+		v.Set("q", query)
+	}
+	u := fmt.Sprintf("http://%s/messages?%s", c.addr, v.Encode())
+
+	var entries []Entry
+	err := c.withBreaker(func() error {
+		return c.retry.do(ctx, func() error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+			if err != nil {
+				return fmt.Errorf("building request: %w", err)
+			}
+
+			body, status, err := c.do(req)
+			if err != nil {
+				return err
+			}
+			if status != http.StatusOK {
+				return classifyStatus(status, body)
+			}
+
+			var v []Entry
+			if err := json.Unmarshal(body, &v); err != nil {
+				return fmt.Errorf("decoding backend response: %w", err)
+			}
+			entries = v
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// PostMessage persists a single guestbook entry. Posts are not retried,
+// since a retried POST could duplicate the message.
+func (c *Client) PostMessage(ctx context.Context, entry Entry) error {
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("serializing message into json: %w", err)
+	}
+
+	return c.withBreaker(func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+			fmt.Sprintf("http://%s/messages", c.addr), bytes.NewReader(payload))
+		if err != nil {
+			return fmt.Errorf("building request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		body, status, err := c.do(req)
+		if err != nil {
+			return err
+		}
+		if status != http.StatusOK {
+			return classifyStatus(status, body)
+		}
+		return nil
+	})
+}
+
+// Ping does a cheap TCP dial of the backend address, for use in
+// readiness probes. It doesn't go through the retry policy or circuit
+// breaker, since a probe should reflect the backend's state right now.
+func (c *Client) Ping(ctx context.Context) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return fmt.Errorf("dialing backend: %w", err)
+	}
+	return conn.Close()
+}
+
+// do executes req and returns its body and status code, translating
+// transport-level failures into an UnavailableError.
+func (c *Client) do(req *http.Request) ([]byte, int, error) {
+	if id, ok := RequestIDFromContext(req.Context()); ok {
+		req.Header.Set("X-Request-ID", id)
+	}
+	if tp, ok := traceParentFromContext(req.Context()); ok {
+		req.Header.Set("traceparent", tp)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, &UnavailableError{Err: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, &UnavailableError{Err: fmt.Errorf("reading response body: %w", err)}
+	}
+	return body, resp.StatusCode, nil
+}
+
+// classifyStatus turns a non-200 backend response into a typed error: 4xx
+// is the caller's fault and is not retried, anything else is treated as a
+// transient backend failure.
+func classifyStatus(status int, body []byte) error {
+	if status >= 400 && status < 500 {
+		return &BadRequestError{StatusCode: status, Body: string(body)}
+	}
+	return &UnavailableError{Err: fmt.Errorf("status %d: %s", status, string(body))}
+}
+
+// withBreaker runs fn through the circuit breaker, short-circuiting with
+// an UnavailableError when the breaker is open.
+func (c *Client) withBreaker(fn func() error) error {
+	if !c.breaker.allow() {
+		return &UnavailableError{Err: fmt.Errorf("circuit breaker open for %s", c.addr)}
+	}
+
+	err := fn()
+	if isBadRequest(err) {
+		// Bad input from the client isn't the backend's fault; don't trip
+		// the breaker on it. A 4xx still shows the backend is answering, so
+		// it counts as a success (which also ends a half-open trial).
+		c.breaker.record(true)
+		return err
+	}
+	c.breaker.record(err == nil)
+	return err
+}
+
+func isBadRequest(err error) bool {
+	var badReq *BadRequestError
+	return errors.As(err, &badReq)
+}
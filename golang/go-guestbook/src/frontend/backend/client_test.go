@@ -0,0 +1,63 @@
+package backend
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithBreakerDoesNotTripOnBadRequest(t *testing.T) {
+	c := &Client{breaker: newBreaker(1, time.Minute)}
+
+	for i := 0; i < 5; i++ {
+		err := c.withBreaker(func() error {
+			return &BadRequestError{StatusCode: 400, Body: "bad input"}
+		})
+		if !isBadRequest(err) {
+			t.Fatalf("call %d: expected a BadRequestError, got %v", i, err)
+		}
+	}
+
+	// A flood of bad requests must not have tripped the breaker: a
+	// genuine backend failure should still be allowed through.
+	if !c.breaker.allow() {
+		t.Fatal("expected breaker to still be closed after repeated bad requests")
+	}
+}
+
+func TestWithBreakerTripsOnUnavailable(t *testing.T) {
+	c := &Client{breaker: newBreaker(1, time.Minute)}
+
+	err := c.withBreaker(func() error {
+		return &UnavailableError{Err: errors.New("connection refused")}
+	})
+	if err == nil {
+		t.Fatal("expected an error to be returned")
+	}
+
+	if c.breaker.allow() {
+		t.Fatal("expected breaker to be open after a genuine backend failure")
+	}
+}
+
+func TestWithBreakerShortCircuitsWhenOpen(t *testing.T) {
+	c := &Client{breaker: newBreaker(1, time.Minute)}
+
+	_ = c.withBreaker(func() error {
+		return &UnavailableError{Err: errors.New("boom")}
+	})
+
+	called := false
+	err := c.withBreaker(func() error {
+		called = true
+		return nil
+	})
+
+	if called {
+		t.Fatal("expected fn not to be called while the breaker is open")
+	}
+	var unavailable *UnavailableError
+	if !errors.As(err, &unavailable) {
+		t.Fatalf("expected an UnavailableError from the open breaker, got %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package backend
+
+import (
+	"context"
+	"time"
+)
+
+// retryPolicy retries an idempotent operation with exponential backoff. It
+// never retries a *BadRequestError, since that indicates bad input rather
+// than a transient failure.
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+var defaultRetryPolicy = retryPolicy{
+	maxAttempts: 3,
+	baseDelay:   100 * time.Millisecond,
+	maxDelay:    2 * time.Second,
+}
+
+// do runs fn, retrying on failure according to the policy. It gives up
+// immediately on a *BadRequestError or if ctx is done.
+func (p retryPolicy) do(ctx context.Context, fn func() error) error {
+	var err error
+	delay := p.baseDelay
+
+	for attempt := 1; attempt <= p.maxAttempts; attempt++ {
+		err = fn()
+		if err == nil {
+			return nil
+		}
+		if _, ok := err.(*BadRequestError); ok {
+			return err
+		}
+		if attempt == p.maxAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > p.maxDelay {
+			delay = p.maxDelay
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,85 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyRetriesTransientErrors(t *testing.T) {
+	p := retryPolicy{maxAttempts: 3, baseDelay: time.Millisecond, maxDelay: 5 * time.Millisecond}
+
+	attempts := 0
+	err := p.do(context.Background(), func() error {
+		attempts++
+		if attempts < 3 {
+			return &UnavailableError{Err: errors.New("boom")}
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("expected success after retries, got %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestRetryPolicyGivesUpAfterMaxAttempts(t *testing.T) {
+	p := retryPolicy{maxAttempts: 2, baseDelay: time.Millisecond, maxDelay: 5 * time.Millisecond}
+
+	attempts := 0
+	wantErr := &UnavailableError{Err: errors.New("still broken")}
+	err := p.do(context.Background(), func() error {
+		attempts++
+		return wantErr
+	})
+
+	if err != wantErr {
+		t.Fatalf("expected the last error to be returned, got %v", err)
+	}
+	if attempts != 2 {
+		t.Fatalf("expected exactly maxAttempts attempts, got %d", attempts)
+	}
+}
+
+func TestRetryPolicyDoesNotRetryBadRequest(t *testing.T) {
+	p := retryPolicy{maxAttempts: 3, baseDelay: time.Millisecond, maxDelay: 5 * time.Millisecond}
+
+	attempts := 0
+	badReq := &BadRequestError{StatusCode: 400, Body: "nope"}
+	err := p.do(context.Background(), func() error {
+		attempts++
+		return badReq
+	})
+
+	if err != badReq {
+		t.Fatalf("expected the BadRequestError to be returned unchanged, got %v", err)
+	}
+	if attempts != 1 {
+		t.Fatalf("expected a BadRequestError to short-circuit after one attempt, got %d attempts", attempts)
+	}
+}
+
+func TestRetryPolicyStopsOnContextCancellation(t *testing.T) {
+	p := retryPolicy{maxAttempts: 5, baseDelay: 50 * time.Millisecond, maxDelay: time.Second}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	err := p.do(ctx, func() error {
+		attempts++
+		if attempts == 1 {
+			cancel()
+		}
+		return &UnavailableError{Err: errors.New("boom")}
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if attempts != 1 {
+		t.Fatalf("expected to stop retrying once the context was canceled, got %d attempts", attempts)
+	}
+}
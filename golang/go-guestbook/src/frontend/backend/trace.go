@@ -0,0 +1,36 @@
+package backend
+
+import "context"
+
+// ctxKey namespaces values this package stores on a context, so it can't
+// collide with keys set by other packages.
+type ctxKey int
+
+const (
+	requestIDKey ctxKey = iota
+	traceParentKey
+)
+
+// ContextWithRequestID attaches the request ID to forward as the
+// X-Request-ID header on outbound backend calls made with ctx.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestIDFromContext returns the request ID previously attached with
+// ContextWithRequestID, if any.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey).(string)
+	return id, ok
+}
+
+// ContextWithTraceParent attaches a W3C traceparent value to forward on
+// outbound backend calls made with ctx.
+func ContextWithTraceParent(ctx context.Context, traceParent string) context.Context {
+	return context.WithValue(ctx, traceParentKey, traceParent)
+}
+
+func traceParentFromContext(ctx context.Context) (string, bool) {
+	tp, ok := ctx.Value(traceParentKey).(string)
+	return tp, ok
+}
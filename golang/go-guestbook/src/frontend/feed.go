@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/xml"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// pageRequest captures the paging and search parameters accepted by the
+// home page, the search page, and the syndication feeds.
+type pageRequest struct {
+	Page  int
+	Limit int
+	Query string
+}
+
+// parsePageRequest reads page, limit, and q from the request's query
+// string, clamping page/limit to sane bounds.
+func parsePageRequest(r *http.Request) pageRequest {
+	pr := pageRequest{
+		Page:  1,
+		Limit: defaultPageLimit,
+		Query: r.URL.Query().Get("q"),
+	}
+
+	if p, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && p > 0 {
+		pr.Page = p
+	}
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		pr.Limit = l
+	}
+	if pr.Limit > maxPageLimit {
+		pr.Limit = maxPageLimit
+	}
+
+	return pr
+}
+
+// queryString renders the page request as the query string to forward to
+// the backend's /messages endpoint.
+func (pr pageRequest) queryString() string {
+	v := url.Values{}
+	v.Set("page", strconv.Itoa(pr.Page))
+	v.Set("limit", strconv.Itoa(pr.Limit))
+	if pr.Query != "" {
+		v.Set("q", pr.Query)
+	}
+	return v.Encode()
+}
+
+type feedFormat int
+
+const (
+	feedFormatNone feedFormat = iota
+	feedFormatAtom
+	feedFormatRSS
+)
+
+// wantsFeed reports whether the request is asking for a syndication feed
+// via its Accept header rather than the HTML template.
+func wantsFeed(r *http.Request) bool {
+	return feedFormatFromAccept(r) != feedFormatNone
+}
+
+// feedFormatFromAccept maps an Accept header to a feed format, defaulting
+// to Atom when the client asks for plain "application/xml" or "text/xml".
+// The header must be exactly one of the matched media types; lists and
+// parameters (such as ";q=0.9") are not parsed and give feedFormatNone.
+func feedFormatFromAccept(r *http.Request) feedFormat {
+	switch accept := r.Header.Get("Accept"); {
+	case accept == "application/rss+xml":
+		return feedFormatRSS
+	case accept == "application/atom+xml", accept == "application/xml", accept == "text/xml":
+		return feedFormatAtom
+	default:
+		return feedFormatNone
+	}
+}
+
+// feedHandler returns an http.HandlerFunc that fetches the requested page
+// of entries from the backend and serves it as an Atom or RSS feed.
+func (f *frontendServer) feedHandler(format feedFormat) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pr := parsePageRequest(r)
+		entries, err := f.listEntries(r.Context(), pr)
+		if err != nil {
+			httpErrorFromBackend(w, err)
+			return
+		}
+
+		switch format {
+		case feedFormatRSS:
+			writeRSSFeed(w, entries)
+		default:
+			writeAtomFeed(w, entries)
+		}
+	}
+}
+
+// atomFeed and its nested types model just enough of the Atom 1.0 spec to
+// syndicate the guestbook.
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Title  string `xml:"title"`
+	Author struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+	Updated string `xml:"updated"`
+	Content string `xml:"content"`
+}
+
+func writeAtomFeed(w http.ResponseWriter, entries []guestbookEntry) {
+	feed := atomFeed{
+		Xmlns: "http://www.w3.org/2005/Atom",
+		Title: "Guestbook",
+	}
+	if len(entries) > 0 {
+		feed.Updated = entries[0].Date.Format("2006-01-02T15:04:05Z07:00")
+	}
+	for _, e := range entries {
+		entry := atomEntry{
+			Title:   "Message from " + e.Author,
+			Content: e.Message,
+			Updated: e.Date.Format("2006-01-02T15:04:05Z07:00"),
+		}
+		entry.Author.Name = e.Author
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	writeXML(w, feed)
+}
+
+// rssFeed and its nested types model just enough of RSS 2.0 to syndicate
+// the guestbook.
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title string    `xml:"title"`
+	Items []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Author      string `xml:"author"`
+	Description string `xml:"description"`
+	PubDate     string `xml:"pubDate"`
+}
+
+func writeRSSFeed(w http.ResponseWriter, entries []guestbookEntry) {
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{Title: "Guestbook"},
+	}
+	for _, e := range entries {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       "Message from " + e.Author,
+			Author:      e.Author,
+			Description: e.Message,
+			PubDate:     e.Date.Format(http.TimeFormat),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	writeXML(w, feed)
+}
+
+// writeXML marshals v to w as XML, logging (but not surfacing) encoding
+// failures since the response headers are already committed.
+func writeXML(w http.ResponseWriter, v interface{}) {
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		log.Printf("WARNING: failed to encode feed xml: %+v", err)
+	}
+}
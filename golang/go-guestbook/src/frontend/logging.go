@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/iantalarico/cloud-code-samples/golang/go-guestbook/src/frontend/backend"
+)
+
+// logger emits structured JSON log records to stdout for ingestion by
+// Cloud Logging. It's assigned in main before any requests are served.
+var logger *slog.Logger
+
+// withTracing wraps next with structured request logging and propagates
+// an X-Request-ID / W3C traceparent through the request context, so that
+// downstream backend calls and log lines can be correlated.
+func withTracing(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requestsInFlight.Inc()
+		defer requestsInFlight.Dec()
+
+		start := time.Now()
+
+		reqID := r.Header.Get("X-Request-ID")
+		if reqID == "" {
+			reqID = newRequestID()
+		}
+		traceParent := r.Header.Get("traceparent")
+		if traceParent == "" {
+			traceParent = newTraceParent()
+		} else {
+			traceParent = childTraceParent(traceParent)
+		}
+
+		ctx := backend.ContextWithRequestID(r.Context(), reqID)
+		ctx = backend.ContextWithTraceParent(ctx, traceParent)
+		r = r.WithContext(ctx)
+
+		w.Header().Set("X-Request-ID", reqID)
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r)
+		duration := time.Since(start)
+
+		recordRequestMetrics(r.Method, r.URL.Path, rec.status, duration)
+		logger.Info("handled request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"duration_ms", duration.Milliseconds(),
+			"request_id", reqID,
+		)
+	}
+}
+
+// statusRecorder wraps an http.ResponseWriter to capture the status code
+// written, which the stdlib doesn't otherwise expose to middleware.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// newRequestID generates a short opaque request identifier.
+func newRequestID() string { return randHex(8) }
+
+// newTraceParent generates a fresh W3C traceparent header value, as the
+// root span of a new trace.
+func newTraceParent() string {
+	return strings.Join([]string{"00", randHex(16), randHex(8), "01"}, "-")
+}
+
+// childTraceParent derives a traceparent for this hop from an incoming
+// one, keeping the trace ID but minting a new span ID.
+func childTraceParent(parent string) string {
+	parts := strings.Split(parent, "-")
+	if len(parts) != 4 || len(parts[1]) != 32 {
+		return newTraceParent()
+	}
+	return strings.Join([]string{parts[0], parts[1], randHex(8), parts[3]}, "-")
+}
+
+func randHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand.Read only fails if the OS entropy source is
+		// broken, which we can't recover from; fall back to zeros
+		// rather than panicking the request.
+		return strings.Repeat("0", n*2)
+	}
+	return hex.EncodeToString(b)
+}
@@ -1,16 +1,20 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"html/template"
-	"io/ioutil"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/iantalarico/cloud-code-samples/golang/go-guestbook/src/frontend/backend"
 )
 
 var (
@@ -19,11 +23,14 @@ var (
 )
 
 // guestbookEntry represents the message object returned from the backend API.
-type guestbookEntry struct {
-	Author  string    `json:"author"`
-	Message string    `json:"message"`
-	Date    time.Time `json:"date"`
-}
+type guestbookEntry = backend.Entry
+
+// postRateLimitBurst and postRateLimitPerSecond bound how often a single
+// client IP can submit the guestbook form.
+const (
+	postRateLimitBurst     = 5
+	postRateLimitPerSecond = 0.5
+)
 
 // main starts a frontend server and connects to the backend.
 func main() {
@@ -47,23 +54,52 @@ func main() {
 	}
 	tpl = t
 
-	fe := &frontendServer{backendAddr: backendAddr}
-	http.HandleFunc("/", fe.homeHandler)
-	http.HandleFunc("/post", fe.postHandler)
+	logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+	fe := &frontendServer{
+		backend:     backend.New(backendAddr, newBackendHTTPClient()),
+		rateLimiter: newIPRateLimiter(postRateLimitBurst, postRateLimitPerSecond),
+	}
+	http.HandleFunc("/", withTracing(fe.homeHandler))
+	http.HandleFunc("/post", withTracing(fe.postHandler))
+	http.HandleFunc("/search", withTracing(fe.searchHandler))
+	http.HandleFunc("/feed.atom", withTracing(fe.feedHandler(feedFormatAtom)))
+	http.HandleFunc("/feed.rss", withTracing(fe.feedHandler(feedFormatRSS)))
+	http.Handle("/metrics", promhttp.Handler())
+	http.HandleFunc("/healthz", healthzHandler)
+	http.HandleFunc("/readyz", fe.readyzHandler)
+
+	runWithGracefulShutdown(newHTTPServer(port))
+}
 
-	log.Printf("frontend server listening on port %s", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		log.Fatalf("server listen error: %+v", err)
+// newBackendHTTPClient builds the *http.Client used for all backend calls,
+// with explicit dial/response timeouts and pooled connections so a slow or
+// dead backend can't pile up goroutines on the frontend.
+func newBackendHTTPClient() *http.Client {
+	transport := &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout:   2 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   10,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   2 * time.Second,
+		ResponseHeaderTimeout: 3 * time.Second,
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   5 * time.Second,
 	}
 }
 
 type frontendServer struct {
-	backendAddr string
+	backend     *backend.Client
+	rateLimiter *ipRateLimiter
 }
 
 // homeHandler handles GET requests to /.
 func (f *frontendServer) homeHandler(w http.ResponseWriter, r *http.Request) {
-	log.Printf("received request: %s %s", r.Method, r.URL.Path)
 	if r.Method != http.MethodGet {
 		http.Error(w, fmt.Sprintf("only GET requests are supported (got %s)", r.Method), http.StatusMethodNotAllowed)
 		return
@@ -72,85 +108,163 @@ func (f *frontendServer) homeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("querying backend for entries")
-	resp, err := http.Get(fmt.Sprintf("http://%s/messages", f.backendAddr))
-	if err != nil {
-		http.Error(w, fmt.Sprintf("querying backend failed: %+v", err), http.StatusInternalServerError)
-		return
-	}
-	defer resp.Body.Close()
+	f.listAndRenderHome(w, r)
+}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("failed to read response body: %+v", err), http.StatusInternalServerError)
+// searchHandler handles GET requests to /search, which is the home page
+// filtered by the "q" query parameter.
+func (f *frontendServer) searchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, fmt.Sprintf("only GET requests are supported (got %s)", r.Method), http.StatusMethodNotAllowed)
 		return
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		http.Error(w, fmt.Sprintf("got status code %d from the backend: %s", resp.StatusCode, string(body)), http.StatusInternalServerError)
+	f.listAndRenderHome(w, r)
+}
+
+// listAndRenderHome fetches the current page of entries for r and renders
+// them, either as the home template or as a feed if the client asked for
+// one. Shared by homeHandler and searchHandler, which differ only in
+// their method/path guard.
+func (f *frontendServer) listAndRenderHome(w http.ResponseWriter, r *http.Request) {
+	if wantsFeed(r) {
+		f.feedHandler(feedFormatFromAccept(r))(w, r)
 		return
 	}
 
-	log.Printf("parsing backend response into json")
-	var v []guestbookEntry
-	if err := json.Unmarshal(body, &v); err != nil {
-		log.Printf("WARNING: failed to decode json from the api: %+v input=%q", err, string(body))
-		http.Error(w,
-			fmt.Sprintf("could not decode json response from the api: %+v", err),
-			http.StatusInternalServerError)
+	pr := parsePageRequest(r)
+	v, err := f.listEntries(r.Context(), pr)
+	if err != nil {
+		httpErrorFromBackend(w, err)
 		return
 	}
 
-	log.Printf("retrieved %d messages from the backend api", len(v))
+	f.renderHome(w, r, pr, v, nil, "", "")
+}
+
+// renderHome executes the "home" template with the given page of entries.
+// errs, formName, and formMessage repopulate the post form when re-
+// rendering after a validation failure; they're zero values otherwise.
+func (f *frontendServer) renderHome(w http.ResponseWriter, r *http.Request, pr pageRequest, entries []guestbookEntry, errs formErrors, formName, formMessage string) {
 	if err := tpl.ExecuteTemplate(w, "home", map[string]interface{}{
-		"messages": v,
+		"messages":    entries,
+		"query":       pr.Query,
+		"page":        pr.Page,
+		"limit":       pr.Limit,
+		"csrfToken":   csrfTokenFor(w, r),
+		"errors":      errs,
+		"formName":    formName,
+		"formMessage": formMessage,
 	}); err != nil {
-		log.Printf("WARNING: failed to render html template: %+v", err)
+		loggerFor(r.Context()).Error("failed to render html template", "error", err)
 	}
 }
 
+// listEntries fetches a page of entries from the backend, forwarding the
+// page, limit, and search query parameters.
+func (f *frontendServer) listEntries(ctx context.Context, pr pageRequest) ([]guestbookEntry, error) {
+	start := time.Now()
+	v, err := f.backend.ListMessages(ctx, pr.Page, pr.Limit, pr.Query)
+	latency := time.Since(start)
+
+	if err != nil {
+		recordBackendCall("list_messages", "error", latency)
+		loggerFor(ctx).Error("querying backend for entries failed",
+			"backend_latency_ms", latency.Milliseconds(), "error", err)
+		return nil, err
+	}
+
+	recordBackendCall("list_messages", "success", latency)
+	loggerFor(ctx).Info("retrieved messages from the backend api",
+		"backend_latency_ms", latency.Milliseconds(), "count", len(v))
+	return v, nil
+}
+
+// loggerFor returns the package logger annotated with the request ID
+// carried on ctx, if any, so log lines can be correlated back to the
+// request that produced them.
+func loggerFor(ctx context.Context) *slog.Logger {
+	if id, ok := backend.RequestIDFromContext(ctx); ok {
+		return logger.With("request_id", id)
+	}
+	return logger
+}
+
+// httpErrorFromBackend classifies an error from the backend package and
+// writes the appropriate status code: bad input from the backend's point
+// of view is a 400, anything else is a 503 since the backend (or the
+// network to it) is the thing that's actually broken.
+func httpErrorFromBackend(w http.ResponseWriter, err error) {
+	var badReq *backend.BadRequestError
+	if errors.As(err, &badReq) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, "guestbook temporarily unavailable, please try again shortly", http.StatusServiceUnavailable)
+}
+
 // postHandler handles POST requests to /messages.
 func (f *frontendServer) postHandler(w http.ResponseWriter, r *http.Request) {
-	log.Printf("received request: %s %s", r.Method, r.URL.Path)
 	if r.Method != http.MethodPost {
 		http.Error(w, "only POST requests are supported", http.StatusMethodNotAllowed)
 		return
 	}
 
-	if err := f.saveMessage(r.FormValue("name"), r.FormValue("message")); err != nil {
-		http.Error(w, fmt.Sprintf("failed to save message: %+v", err), http.StatusBadRequest)
+	if !f.rateLimiter.allow(clientIP(r)) {
+		http.Error(w, "too many messages, please slow down", http.StatusTooManyRequests)
 		return
-	} else {
-		// redirect to homepage
-		http.Redirect(w, r, "/", http.StatusFound)
 	}
-}
 
-// saveMessage makes a request to the backend to persist the message.
-func (f *frontendServer) saveMessage(author, message string) error {
-	if author == "" {
-		return errors.New("Please enter your name.")
-	} else if message == "" {
-		return errors.New("Please write a message.")
+	if !verifyCSRF(r) {
+		http.Error(w, "invalid or missing csrf token", http.StatusForbidden)
+		return
 	}
 
-	entry := guestbookEntry{
-		Author:  author,
-		Message: message,
+	name, message := r.FormValue("name"), r.FormValue("message")
+	if errs := validatePost(name, message); len(errs) > 0 {
+		f.renderHomeWithFormErrors(w, r, errs, name, message)
+		return
+	}
+
+	if err := f.saveMessage(r.Context(), name, message); err != nil {
+		httpErrorFromBackend(w, err)
+		return
 	}
-	body, err := json.Marshal(entry)
+
+	// redirect to homepage
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+// renderHomeWithFormErrors re-renders the home template after a
+// validation failure, so the user sees field-level errors and their
+// submitted values instead of a raw 400.
+func (f *frontendServer) renderHomeWithFormErrors(w http.ResponseWriter, r *http.Request, errs formErrors, name, message string) {
+	pr := parsePageRequest(r)
+	entries, err := f.listEntries(r.Context(), pr)
 	if err != nil {
-		return fmt.Errorf("failed to serialize message into json: %+v", err)
+		httpErrorFromBackend(w, err)
+		return
 	}
+	f.renderHome(w, r, pr, entries, errs, name, message)
+}
+
+// saveMessage makes a request to the backend to persist the message.
+// Callers are expected to have already validated author/message.
+func (f *frontendServer) saveMessage(ctx context.Context, author, message string) error {
+	start := time.Now()
+	err := f.backend.PostMessage(ctx, guestbookEntry{
+		Author:  author,
+		Message: message,
+	})
+	latency := time.Since(start)
 
-	resp, err := http.Post(fmt.Sprintf("http://%s/messages", f.backendAddr),
-		"application/json", bytes.NewReader(body))
 	if err != nil {
-		return fmt.Errorf("backend returned failure: %+v", err)
-	} else if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code from backend: %d %v", resp.StatusCode, resp.Status)
+		recordBackendCall("post_message", "error", latency)
+		loggerFor(ctx).Error("posting message to backend failed", "backend_latency_ms", latency.Milliseconds(), "error", err)
+		return err
 	}
-	defer resp.Body.Close()
+	recordBackendCall("post_message", "success", latency)
+	loggerFor(ctx).Info("posted message to backend", "backend_latency_ms", latency.Milliseconds())
 	return nil
 }
 
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "frontend_http_requests_total",
+		Help: "Total number of HTTP requests handled by the frontend, by method, path, and status code.",
+	}, []string{"method", "path", "status"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "frontend_http_request_duration_seconds",
+		Help:    "End-to-end latency of HTTP requests handled by the frontend.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "path"})
+
+	requestsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "frontend_http_requests_in_flight",
+		Help: "Number of HTTP requests currently being handled by the frontend.",
+	})
+
+	backendCallDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "frontend_backend_call_duration_seconds",
+		Help:    "Latency of calls from the frontend to the guestbook backend API.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation", "outcome"})
+)
+
+func init() {
+	prometheus.MustRegister(requestsTotal, requestDuration, requestsInFlight, backendCallDuration)
+}
+
+// recordBackendCall records the duration of a call to the backend API for
+// the /metrics endpoint, labeled by operation (e.g. "list_messages") and
+// outcome ("success" or "error").
+func recordBackendCall(operation string, outcome string, d time.Duration) {
+	backendCallDuration.WithLabelValues(operation, outcome).Observe(d.Seconds())
+}
+
+// knownMetricsPaths are the routes registered on the mux through
+// withTracing, the only handlers that record request metrics. "/" is a
+// catch-all subtree handler, so anything not in this set is a path
+// http.ServeMux routed to homeHandler without actually matching a route.
+var knownMetricsPaths = map[string]bool{
+	"/":          true,
+	"/post":      true,
+	"/search":    true,
+	"/feed.atom": true,
+	"/feed.rss":  true,
+}
+
+// metricsPathLabel normalizes an incoming request path to a bounded label
+// value, collapsing anything outside the routes we actually registered
+// into "other" so a stray or malicious path can't mint a new, permanent
+// Prometheus series per request.
+func metricsPathLabel(path string) string {
+	if knownMetricsPaths[path] {
+		return path
+	}
+	return "other"
+}
+
+// knownMetricsMethods are the HTTP methods the server actually dispatches
+// on. Anything else is a client sending garbage and is bucketed into
+// "other" for the same reason metricsPathLabel bounds the path label.
+var knownMetricsMethods = map[string]bool{
+	http.MethodGet:  true,
+	http.MethodPost: true,
+}
+
+// metricsMethodLabel normalizes an incoming request method to a bounded
+// label value.
+func metricsMethodLabel(method string) string {
+	if knownMetricsMethods[method] {
+		return method
+	}
+	return "other"
+}
+
+// recordRequestMetrics records the outcome of a completed HTTP request for
+// the /metrics endpoint.
+func recordRequestMetrics(method, path string, status int, d time.Duration) {
+	methodLabel := metricsMethodLabel(method)
+	pathLabel := metricsPathLabel(path)
+	requestsTotal.WithLabelValues(methodLabel, pathLabel, strconv.Itoa(status)).Inc()
+	requestDuration.WithLabelValues(methodLabel, pathLabel).Observe(d.Seconds())
+}
+
+// healthzHandler reports whether the process itself is alive. It never
+// depends on the backend, so Kubernetes doesn't restart a frontend pod
+// just because the backend is briefly unavailable.
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// readyzHandler reports whether the frontend is ready to serve traffic: it
+// does a cheap TCP probe of the backend and returns 503 until the backend
+// is reachable, so a rolling update doesn't send traffic to a pod that
+// can't yet reach its dependency.
+func (f *frontendServer) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	if err := f.backend.Ping(ctx); err != nil {
+		http.Error(w, "backend not reachable: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenBucket is a classic token-bucket limiter: it holds up to capacity
+// tokens, refilling at refillPerSec, and each allowed call consumes one.
+type tokenBucket struct {
+	capacity     float64
+	refillPerSec float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(capacity, refillPerSec float64) *tokenBucket {
+	return &tokenBucket{capacity: capacity, refillPerSec: refillPerSec, tokens: capacity, last: time.Now()}
+}
+
+func (b *tokenBucket) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.refillPerSec
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// ipRateLimiter enforces a per-client-IP token bucket, so a single client
+// can't flood the backend with POSTs. Buckets are created lazily and kept
+// for the life of the process; this sample doesn't expect enough distinct
+// clients to make that a memory concern.
+type ipRateLimiter struct {
+	capacity     float64
+	refillPerSec float64
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+func newIPRateLimiter(capacity, refillPerSec float64) *ipRateLimiter {
+	return &ipRateLimiter{
+		capacity:     capacity,
+		refillPerSec: refillPerSec,
+		buckets:      map[string]*tokenBucket{},
+	}
+}
+
+func (l *ipRateLimiter) allow(ip string) bool {
+	l.mu.Lock()
+	b, ok := l.buckets[ip]
+	if !ok {
+		b = newTokenBucket(l.capacity, l.refillPerSec)
+		l.buckets[ip] = b
+	}
+	l.mu.Unlock()
+
+	return b.allow()
+}
+
+// trustedProxyHops is the number of reverse proxies known to sit in front
+// of this service and append their own entry to X-Forwarded-For (the GKE
+// ingress). The leftmost entries in that header are whatever the original
+// client claimed and must never be trusted; only the Nth-from-the-right
+// entry is one our own infrastructure actually appended.
+const trustedProxyHops = 1
+
+// clientIP returns the address the rate limiter should key on: the hop
+// added by our own trusted proxy chain in X-Forwarded-For, falling back
+// to the direct connection's address when the header is absent or too
+// short to contain that hop.
+func clientIP(r *http.Request) string {
+	if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+		parts := strings.Split(fwd, ",")
+		if len(parts) >= trustedProxyHops {
+			if ip := strings.TrimSpace(parts[len(parts)-trustedProxyHops]); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketAllowsUpToCapacityThenBlocks(t *testing.T) {
+	b := newTokenBucket(3, 1)
+
+	for i := 0; i < 3; i++ {
+		if !b.allow() {
+			t.Fatalf("call %d: expected bucket to allow calls up to capacity", i)
+		}
+	}
+	if b.allow() {
+		t.Fatal("expected bucket to block once capacity is exhausted")
+	}
+}
+
+func TestTokenBucketRefillsOverTime(t *testing.T) {
+	b := newTokenBucket(2, 10) // 10 tokens/sec
+
+	if !b.allow() || !b.allow() {
+		t.Fatal("expected the first two calls to be allowed")
+	}
+	if b.allow() {
+		t.Fatal("expected the bucket to be empty")
+	}
+
+	// Simulate 200ms of elapsed time without sleeping: at 10/sec that's 2
+	// tokens back.
+	b.mu.Lock()
+	b.last = b.last.Add(-200 * time.Millisecond)
+	b.mu.Unlock()
+
+	if !b.allow() {
+		t.Fatal("expected the bucket to have refilled after enough elapsed time")
+	}
+}
+
+func TestTokenBucketRefillCapsAtCapacity(t *testing.T) {
+	b := newTokenBucket(2, 100)
+
+	if !b.allow() || !b.allow() {
+		t.Fatal("expected the first two calls to be allowed")
+	}
+
+	// Simulate a very long idle period; refill must cap at capacity
+	// rather than accumulating an unbounded number of tokens.
+	b.mu.Lock()
+	b.last = b.last.Add(-time.Hour)
+	b.mu.Unlock()
+
+	if !b.allow() || !b.allow() {
+		t.Fatal("expected exactly capacity tokens to be available after refilling")
+	}
+	if b.allow() {
+		t.Fatal("expected refill to be capped at capacity, not accumulate unbounded tokens")
+	}
+}
+
+func TestClientIPUsesTrustedProxyHop(t *testing.T) {
+	r := httptest.NewRequest("POST", "/post", nil)
+	r.Header.Set("X-Forwarded-For", "203.0.113.5, 10.0.0.1")
+	r.RemoteAddr = "10.0.0.1:12345"
+
+	if got, want := clientIP(r), "10.0.0.1"; got != want {
+		t.Fatalf("clientIP() = %q, want %q", got, want)
+	}
+}
+
+func TestClientIPIgnoresClientSuppliedLeftmostEntry(t *testing.T) {
+	r := httptest.NewRequest("POST", "/post", nil)
+	// An attacker can freely set the leftmost entries; only the
+	// trustedProxyHops-th from the right was actually appended by our
+	// infrastructure and should be trusted.
+	r.Header.Set("X-Forwarded-For", "attacker-spoofed-1, attacker-spoofed-2, 198.51.100.9")
+	r.RemoteAddr = "198.51.100.9:5555"
+
+	if got, want := clientIP(r), "198.51.100.9"; got != want {
+		t.Fatalf("clientIP() = %q, want %q (spoofed leftmost entries must be ignored)", got, want)
+	}
+}
+
+func TestClientIPHandlesSingleEntryForwardedFor(t *testing.T) {
+	r := httptest.NewRequest("POST", "/post", nil)
+	r.Header.Set("X-Forwarded-For", "203.0.113.7")
+	r.RemoteAddr = "203.0.113.7:1111"
+
+	if got, want := clientIP(r), "203.0.113.7"; got != want {
+		t.Fatalf("clientIP() = %q, want %q", got, want)
+	}
+}
+
+func TestClientIPFallsBackWithoutForwardedForHeader(t *testing.T) {
+	r := httptest.NewRequest("POST", "/post", nil)
+	r.RemoteAddr = "192.0.2.1:4444"
+
+	if got, want := clientIP(r), "192.0.2.1"; got != want {
+		t.Fatalf("clientIP() = %q, want %q", got, want)
+	}
+}
+
+func TestClientIPFallsBackOnMalformedRemoteAddr(t *testing.T) {
+	r := httptest.NewRequest("POST", "/post", nil)
+	r.RemoteAddr = "not-a-host-port-pair"
+
+	if got, want := clientIP(r), "not-a-host-port-pair"; got != want {
+		t.Fatalf("clientIP() = %q, want %q", got, want)
+	}
+}
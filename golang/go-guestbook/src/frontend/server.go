@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultReadHeaderTimeout = 5 * time.Second
+	defaultReadTimeout       = 10 * time.Second
+	defaultWriteTimeout      = 10 * time.Second
+	defaultIdleTimeout       = 120 * time.Second
+	defaultShutdownTimeout   = 15 * time.Second
+)
+
+// newHTTPServer builds the server's *http.Server with explicit timeouts,
+// configurable via env vars (as Go duration strings, e.g. "5s") so
+// deployments can tune them without a rebuild.
+func newHTTPServer(port string) *http.Server {
+	return &http.Server{
+		Addr:              ":" + port,
+		Handler:           http.DefaultServeMux,
+		ReadHeaderTimeout: durationEnv("READ_HEADER_TIMEOUT", defaultReadHeaderTimeout),
+		ReadTimeout:       durationEnv("READ_TIMEOUT", defaultReadTimeout),
+		WriteTimeout:      durationEnv("WRITE_TIMEOUT", defaultWriteTimeout),
+		IdleTimeout:       durationEnv("IDLE_TIMEOUT", defaultIdleTimeout),
+	}
+}
+
+// durationEnv reads name as a Go duration string, falling back to def if
+// it's unset or malformed.
+func durationEnv(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("WARNING: invalid duration for %s=%q, using default %s: %+v", name, v, def, err)
+		return def
+	}
+	return d
+}
+
+// runWithGracefulShutdown starts server in a background goroutine and
+// blocks until it receives SIGINT or SIGTERM. On signal, it drains
+// in-flight requests via server.Shutdown before returning, so a
+// Kubernetes rolling update doesn't kill POSTs mid-flight.
+func runWithGracefulShutdown(server *http.Server) {
+	go func() {
+		log.Printf("frontend server listening on %s", server.Addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("server listen error: %+v", err)
+		}
+	}()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-sigCh
+
+	shutdownTimeout := durationEnv("SHUTDOWN_TIMEOUT", defaultShutdownTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	logger.Info("shutting down", "signal", sig.String(), "drain_timeout", shutdownTimeout.String())
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Error("graceful shutdown failed", "error", err)
+		return
+	}
+	logger.Info("shutdown complete")
+}
@@ -0,0 +1,38 @@
+package main
+
+import "unicode/utf8"
+
+const (
+	maxAuthorLen  = 100
+	maxMessageLen = 2000
+)
+
+// formErrors maps a form field name to a user-facing validation message.
+type formErrors map[string]string
+
+// validatePost validates the name/message fields submitted to /post,
+// returning one error per invalid field so they can be re-rendered next
+// to the form instead of failing the whole request with a raw 400.
+func validatePost(author, message string) formErrors {
+	errs := formErrors{}
+
+	switch {
+	case author == "":
+		errs["name"] = "Please enter your name."
+	case !utf8.ValidString(author):
+		errs["name"] = "Name contains invalid characters."
+	case len(author) > maxAuthorLen:
+		errs["name"] = "Name is too long."
+	}
+
+	switch {
+	case message == "":
+		errs["message"] = "Please write a message."
+	case !utf8.ValidString(message):
+		errs["message"] = "Message contains invalid characters."
+	case len(message) > maxMessageLen:
+		errs["message"] = "Message is too long."
+	}
+
+	return errs
+}